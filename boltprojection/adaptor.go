@@ -0,0 +1,100 @@
+package boltprojection
+
+import (
+	"context"
+	"time"
+
+	"github.com/dezlitz/projectionkit/internal/identity"
+	"github.com/dogmatiq/dogma"
+	bolt "go.etcd.io/bbolt"
+)
+
+// MessageHandler is a specialization of dogma.ProjectionMessageHandler that
+// is designed to be used with a BoltDB database.
+//
+// Unlike dogma.ProjectionMessageHandler, it is not responsible for tracking
+// the OCC "resource versions" used to make event handling idempotent; that
+// concern is handled transparently by this package.
+type MessageHandler interface {
+	// Configure produces a configuration for this handler by calling
+	// methods on the configurer, c.
+	Configure(c dogma.ProjectionConfigurer)
+
+	// HandleEvent updates the projection to reflect the occurrence of an
+	// event within the given BoltDB transaction.
+	HandleEvent(
+		ctx context.Context,
+		tx *bolt.Tx,
+		s dogma.ProjectionEventScope,
+		m dogma.Message,
+	) error
+
+	// TimeoutHint returns a duration that is suitable for computing a
+	// deadline for the handling of the given message by this handler.
+	TimeoutHint(m dogma.Message) time.Duration
+
+	// Compact reduces the size of the projection's data.
+	Compact(ctx context.Context, s dogma.ProjectionCompactScope) error
+}
+
+// New returns a dogma.ProjectionMessageHandler that delegates to h, using db
+// for storage of both the projection's state and the OCC resource versions
+// used to make event handling idempotent.
+//
+// It panics if db is nil.
+func New(h MessageHandler, db *bolt.DB) dogma.ProjectionMessageHandler {
+	if db == nil {
+		panic("db must not be nil")
+	}
+
+	return &adaptor{
+		MessageHandler: h,
+		db:             db,
+		key:            identity.Key(h),
+	}
+}
+
+type adaptor struct {
+	MessageHandler
+
+	db  *bolt.DB
+	key string
+}
+
+func (a *adaptor) HandleEvent(
+	ctx context.Context,
+	r, c, n []byte,
+	s dogma.ProjectionEventScope,
+	m dogma.Message,
+) (ok bool, err error) {
+	err = a.db.Update(func(tx *bolt.Tx) error {
+		ok, err = UpdateVersion(tx, a.key, r, c, n)
+		if err != nil || !ok {
+			return err
+		}
+
+		return a.MessageHandler.HandleEvent(ctx, tx, s, m)
+	})
+
+	return ok, err
+}
+
+func (a *adaptor) ResourceVersion(ctx context.Context, r []byte) (v []byte, err error) {
+	err = a.db.View(func(tx *bolt.Tx) error {
+		v, err = QueryVersion(tx, a.key, r)
+		return err
+	})
+
+	return v, err
+}
+
+func (a *adaptor) CloseResource(ctx context.Context, r []byte) error {
+	return a.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(occBucket)
+		if b == nil {
+			return nil
+		}
+
+		return b.Delete(occKey(a.key, r))
+	})
+}
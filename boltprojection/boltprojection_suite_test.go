@@ -0,0 +1,13 @@
+package boltprojection_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestBoltProjection(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "boltprojection Suite")
+}
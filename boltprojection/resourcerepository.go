@@ -0,0 +1,138 @@
+package boltprojection
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/dezlitz/projectionkit/internal/identity"
+	"github.com/dogmatiq/dogma"
+	bolt "go.etcd.io/bbolt"
+)
+
+// ResourceRepository provides access to the OCC resource versions tracked
+// on behalf of a specific handler, independently of event handling. It is
+// intended for use by administration tooling that needs to inspect or
+// repair a projection's resource versions.
+type ResourceRepository struct {
+	db  *bolt.DB
+	key string
+}
+
+// NewResourceRepository returns a ResourceRepository that manipulates the
+// resource versions tracked on behalf of h, within db.
+func NewResourceRepository(db *bolt.DB, h dogma.ProjectionMessageHandler) *ResourceRepository {
+	return &ResourceRepository{
+		db:  db,
+		key: identity.Key(h),
+	}
+}
+
+// ResourceVersion returns the current version of the resource r.
+func (rr *ResourceRepository) ResourceVersion(ctx context.Context, r []byte) (v []byte, err error) {
+	err = rr.db.View(func(tx *bolt.Tx) error {
+		v, err = QueryVersion(tx, rr.key, r)
+		return err
+	})
+
+	return v, err
+}
+
+// StoreResourceVersion unconditionally sets the version of the resource r
+// to v, bypassing the OCC check performed by HandleEvent.
+func (rr *ResourceRepository) StoreResourceVersion(ctx context.Context, r, v []byte) error {
+	return rr.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(occBucket)
+		if err != nil {
+			return err
+		}
+
+		return b.Put(occKey(rr.key, r), v)
+	})
+}
+
+// DeleteResource removes all information about the resource r.
+func (rr *ResourceRepository) DeleteResource(ctx context.Context, r []byte) error {
+	return rr.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(occBucket)
+		if b == nil {
+			return nil
+		}
+
+		return b.Delete(occKey(rr.key, r))
+	})
+}
+
+// QueryVersions returns the current versions of the given resources, keyed
+// by resource. Resources with no recorded version are omitted from the
+// result.
+func (rr *ResourceRepository) QueryVersions(ctx context.Context, resources [][]byte) (map[string][]byte, error) {
+	versions := map[string][]byte{}
+
+	err := rr.db.View(func(tx *bolt.Tx) error {
+		for _, r := range resources {
+			v, err := QueryVersion(tx, rr.key, r)
+			if err != nil {
+				return err
+			}
+
+			if v != nil {
+				versions[string(r)] = v
+			}
+		}
+
+		return nil
+	})
+
+	return versions, err
+}
+
+// DeleteResources removes all information about the given resources.
+func (rr *ResourceRepository) DeleteResources(ctx context.Context, resources [][]byte) error {
+	return rr.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(occBucket)
+		if b == nil {
+			return nil
+		}
+
+		for _, r := range resources {
+			if err := b.Delete(occKey(rr.key, r)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// ListResources calls fn once for each resource tracked on behalf of the
+// repository's handler, passing the resource and its current version, in
+// ascending key order.
+//
+// Iteration stops, and ListResources returns fn's error, as soon as fn
+// returns a non-nil error.
+func (rr *ResourceRepository) ListResources(ctx context.Context, fn func(resource, version []byte) error) error {
+	return rr.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(occBucket)
+		if b == nil {
+			return nil
+		}
+
+		prefix := occHandlerPrefix(rr.key)
+		c := b.Cursor()
+
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			r := append([]byte(nil), k[len(prefix):]...)
+			if err := fn(r, append([]byte(nil), v...)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Close closes the repository. It does not close the underlying BoltDB
+// database.
+func (rr *ResourceRepository) Close() error {
+	return nil
+}
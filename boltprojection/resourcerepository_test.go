@@ -0,0 +1,130 @@
+package boltprojection_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	. "github.com/dezlitz/projectionkit/boltprojection"
+	"github.com/dogmatiq/dogma"
+	. "github.com/dogmatiq/enginekit/enginetest/stubs"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	bolt "go.etcd.io/bbolt"
+)
+
+var _ = Describe("type ResourceRepository", func() {
+	var (
+		db  *bolt.DB
+		h   dogma.ProjectionMessageHandler
+		rr  *ResourceRepository
+		ctx = context.Background()
+	)
+
+	BeforeEach(func() {
+		f, err := os.CreateTemp("", "boltprojection-*.boltdb")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+
+		db, err = bolt.Open(f.Name(), 0600, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		h = &ProjectionMessageHandlerStub{
+			ConfigureFunc: func(c dogma.ProjectionConfigurer) {
+				c.Identity("<name>", "<key>")
+				c.Routes(
+					dogma.HandlesEvent[EventStub[TypeA]](),
+				)
+			},
+		}
+
+		rr = NewResourceRepository(db, h)
+	})
+
+	AfterEach(func() {
+		os.Remove(db.Path())
+		db.Close()
+	})
+
+	Describe("func ListResources()", func() {
+		It("visits every resource belonging to the handler, regardless of how many there are", func() {
+			const count = 250
+
+			for i := 0; i < count; i++ {
+				r := []byte(fmt.Sprintf("resource-%04d", i))
+				Expect(rr.StoreResourceVersion(ctx, r, []byte("1"))).To(Succeed())
+			}
+
+			seen := map[string][]byte{}
+			Expect(rr.ListResources(ctx, func(r, v []byte) error {
+				seen[string(r)] = v
+				return nil
+			})).To(Succeed())
+
+			Expect(seen).To(HaveLen(count))
+			for i := 0; i < count; i++ {
+				r := fmt.Sprintf("resource-%04d", i)
+				Expect(seen).To(HaveKeyWithValue(r, []byte("1")))
+			}
+		})
+
+		It("does not visit resources belonging to another handler", func() {
+			other := &ProjectionMessageHandlerStub{
+				ConfigureFunc: func(c dogma.ProjectionConfigurer) {
+					c.Identity("<other-name>", "<other-key>")
+					c.Routes(
+						dogma.HandlesEvent[EventStub[TypeA]](),
+					)
+				},
+			}
+			otherRR := NewResourceRepository(db, other)
+
+			Expect(rr.StoreResourceVersion(ctx, []byte("<resource>"), []byte("1"))).To(Succeed())
+			Expect(otherRR.StoreResourceVersion(ctx, []byte("<resource>"), []byte("2"))).To(Succeed())
+
+			var seen []string
+			Expect(rr.ListResources(ctx, func(r, v []byte) error {
+				seen = append(seen, string(r))
+				return nil
+			})).To(Succeed())
+
+			Expect(seen).To(ConsistOf("<resource>"))
+		})
+
+		It("stops iterating as soon as fn returns an error", func() {
+			Expect(rr.StoreResourceVersion(ctx, []byte("a"), []byte("1"))).To(Succeed())
+			Expect(rr.StoreResourceVersion(ctx, []byte("b"), []byte("1"))).To(Succeed())
+
+			boom := fmt.Errorf("<error>")
+			n := 0
+			err := rr.ListResources(ctx, func(r, v []byte) error {
+				n++
+				return boom
+			})
+
+			Expect(err).To(Equal(boom))
+			Expect(n).To(Equal(1))
+		})
+	})
+
+	Describe("func QueryVersions()/DeleteResources()", func() {
+		It("omits resources that have no recorded version", func() {
+			Expect(rr.StoreResourceVersion(ctx, []byte("a"), []byte("1"))).To(Succeed())
+
+			versions, err := rr.QueryVersions(ctx, [][]byte{[]byte("a"), []byte("b")})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versions).To(Equal(map[string][]byte{"a": []byte("1")}))
+		})
+
+		It("removes every given resource", func() {
+			Expect(rr.StoreResourceVersion(ctx, []byte("a"), []byte("1"))).To(Succeed())
+			Expect(rr.StoreResourceVersion(ctx, []byte("b"), []byte("1"))).To(Succeed())
+
+			Expect(rr.DeleteResources(ctx, [][]byte{[]byte("a"), []byte("b")})).To(Succeed())
+
+			versions, err := rr.QueryVersions(ctx, [][]byte{[]byte("a"), []byte("b")})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(versions).To(BeEmpty())
+		})
+	})
+})
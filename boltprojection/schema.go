@@ -0,0 +1,102 @@
+// Package boltprojection contains an implementation of
+// dogma.ProjectionMessageHandler that stores projection state, along with
+// the OCC "resource versions" used to make projections idempotent, in an
+// embedded BoltDB database.
+package boltprojection
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// occBucket is the name of the top-level BoltDB bucket used to store OCC
+// resource versions, keyed by handler and resource.
+var occBucket = []byte("projection.occ")
+
+// CreateSchema creates the BoltDB bucket(s) used to store OCC resource
+// versions within db.
+func CreateSchema(db *bolt.DB) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(occBucket)
+		return err
+	})
+}
+
+// DropSchema removes the BoltDB bucket(s) created by CreateSchema.
+func DropSchema(db *bolt.DB) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		err := tx.DeleteBucket(occBucket)
+		if err == bolt.ErrBucketNotFound {
+			return nil
+		}
+		return err
+	})
+}
+
+// occHandlerPrefix returns the BoltDB key prefix under which every resource
+// version tracked by the handler identified by h is stored.
+//
+// h is length-prefixed, rather than separated from the following bytes by a
+// delimiter, so that no handler's prefix can ever be a prefix of another
+// handler's prefix (which a delimiter alone could not guarantee, since
+// handler identity keys may themselves contain the delimiter byte).
+func occHandlerPrefix(h string) []byte {
+	k := make([]byte, 4, 4+len(h))
+	binary.BigEndian.PutUint32(k, uint32(len(h)))
+	return append(k, h...)
+}
+
+// occKey returns the BoltDB key under which the OCC version of resource r,
+// as tracked by the handler identified by h, is stored.
+func occKey(h string, r []byte) []byte {
+	return append(occHandlerPrefix(h), r...)
+}
+
+// QueryVersion returns the current version of resource r, as tracked by the
+// handler identified by h.
+//
+// It returns a nil version if there is no record of the resource.
+func QueryVersion(tx *bolt.Tx, h string, r []byte) ([]byte, error) {
+	b := tx.Bucket(occBucket)
+	if b == nil {
+		return nil, nil
+	}
+
+	v := b.Get(occKey(h, r))
+	if v == nil {
+		return nil, nil
+	}
+
+	// The slice returned by Get() is only valid for the lifetime of the
+	// transaction, so it must be copied before it is returned to the
+	// caller.
+	return append([]byte(nil), v...), nil
+}
+
+// UpdateVersion updates the version of resource r, as tracked by the handler
+// identified by h, from c to n, as a single read-modify-write operation.
+//
+// If n is empty the resource's version record is deleted entirely.
+//
+// It returns false if the resource's current version is not equal to c,
+// indicating an OCC conflict.
+func UpdateVersion(tx *bolt.Tx, h string, r, c, n []byte) (bool, error) {
+	b, err := tx.CreateBucketIfNotExists(occBucket)
+	if err != nil {
+		return false, err
+	}
+
+	k := occKey(h, r)
+
+	if cur := b.Get(k); !bytes.Equal(cur, c) {
+		return false, nil
+	}
+
+	if len(n) == 0 {
+		return true, b.Delete(k)
+	}
+
+	return true, b.Put(k, n)
+}
@@ -0,0 +1,157 @@
+package boltprojection_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/dezlitz/projectionkit/boltprojection"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	bolt "go.etcd.io/bbolt"
+)
+
+var _ = Describe("func UpdateVersion()", func() {
+	var db *bolt.DB
+
+	BeforeEach(func() {
+		f, err := os.CreateTemp("", "boltprojection-*.boltdb")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+
+		db, err = bolt.Open(f.Name(), 0600, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.Remove(db.Path())
+		db.Close()
+	})
+
+	update := func(h string, r, c, n []byte) (ok bool, err error) {
+		txErr := db.Update(func(tx *bolt.Tx) error {
+			ok, err = UpdateVersion(tx, h, r, c, n)
+			return nil
+		})
+		Expect(txErr).ShouldNot(HaveOccurred())
+		return ok, err
+	}
+
+	query := func(h string, r []byte) []byte {
+		var v []byte
+		Expect(db.View(func(tx *bolt.Tx) (err error) {
+			v, err = QueryVersion(tx, h, r)
+			return err
+		})).To(Succeed())
+		return v
+	}
+
+	It("inserts a new resource when the expected current version is empty", func() {
+		ok, err := update("<handler>", []byte("<resource>"), nil, []byte("1"))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(query("<handler>", []byte("<resource>"))).To(Equal([]byte("1")))
+	})
+
+	It("does not insert when a resource already exists and the expected current version is empty", func() {
+		_, err := update("<handler>", []byte("<resource>"), nil, []byte("1"))
+		Expect(err).ShouldNot(HaveOccurred())
+
+		ok, err := update("<handler>", []byte("<resource>"), nil, []byte("2"))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(query("<handler>", []byte("<resource>"))).To(Equal([]byte("1")))
+	})
+
+	It("updates an existing resource when the expected current version matches", func() {
+		_, err := update("<handler>", []byte("<resource>"), nil, []byte("1"))
+		Expect(err).ShouldNot(HaveOccurred())
+
+		ok, err := update("<handler>", []byte("<resource>"), []byte("1"), []byte("2"))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(query("<handler>", []byte("<resource>"))).To(Equal([]byte("2")))
+	})
+
+	It("reports a conflict when the expected current version does not match", func() {
+		_, err := update("<handler>", []byte("<resource>"), nil, []byte("1"))
+		Expect(err).ShouldNot(HaveOccurred())
+
+		ok, err := update("<handler>", []byte("<resource>"), []byte("999"), []byte("2"))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(query("<handler>", []byte("<resource>"))).To(Equal([]byte("1")))
+	})
+
+	It("deletes the resource when the next version is empty", func() {
+		_, err := update("<handler>", []byte("<resource>"), nil, []byte("1"))
+		Expect(err).ShouldNot(HaveOccurred())
+
+		ok, err := update("<handler>", []byte("<resource>"), []byte("1"), nil)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(query("<handler>", []byte("<resource>"))).To(BeNil())
+	})
+
+	It("re-applies the same event idempotently", func() {
+		// A handler re-delivered the same event twice should see the first
+		// call succeed and the second report a conflict, since the current
+		// version has already moved on.
+		ok1, err := update("<handler>", []byte("<resource>"), nil, []byte("1"))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(ok1).To(BeTrue())
+
+		ok2, err := update("<handler>", []byte("<resource>"), nil, []byte("1"))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(ok2).To(BeFalse())
+	})
+
+	It("does not allow one handler's resources to collide with another's", func() {
+		// Regression test: handler keys are length-prefixed so that "foo"
+		// cannot be confused with the prefix of "foobar" for the purposes
+		// of resource version storage.
+		_, err := update("foo", []byte("<resource>"), nil, []byte("1"))
+		Expect(err).ShouldNot(HaveOccurred())
+
+		ok, err := update("foobar", []byte("<resource>"), nil, []byte("2"))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+
+		Expect(query("foo", []byte("<resource>"))).To(Equal([]byte("1")))
+		Expect(query("foobar", []byte("<resource>"))).To(Equal([]byte("2")))
+	})
+
+	It("does not find a resource outside of its bucket before CreateSchema is called", func() {
+		f, err := os.CreateTemp("", "boltprojection-*.boltdb")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+		defer os.Remove(f.Name())
+
+		fresh, err := bolt.Open(f.Name(), 0600, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+		defer fresh.Close()
+
+		var v []byte
+		Expect(fresh.View(func(tx *bolt.Tx) (err error) {
+			v, err = QueryVersion(tx, "<handler>", []byte("<resource>"))
+			return err
+		})).To(Succeed())
+		Expect(v).To(BeNil())
+	})
+})
+
+var _ = Describe("func CreateSchema()/DropSchema()", func() {
+	It("is safe to drop a schema that was never created", func() {
+		f, err := os.CreateTemp("", "boltprojection-*.boltdb")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+		defer os.Remove(f.Name())
+
+		db, err := bolt.Open(filepath.Clean(f.Name()), 0600, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+		defer db.Close()
+
+		Expect(DropSchema(db)).To(Succeed())
+		Expect(CreateSchema(db)).To(Succeed())
+		Expect(DropSchema(db)).To(Succeed())
+	})
+})
@@ -0,0 +1,219 @@
+package dynamoprojection
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/dezlitz/projectionkit/internal/identity"
+	"github.com/dogmatiq/dogma"
+)
+
+// MessageHandler is a specialization of dogma.ProjectionMessageHandler that
+// is designed to be used with a DynamoDB table.
+//
+// Unlike dogma.ProjectionMessageHandler, it is not responsible for tracking
+// the OCC "resource versions" used to make event handling idempotent; that
+// concern is handled transparently by this package. Instead, HandleEvent
+// returns the set of additional transact items needed to apply the event to
+// the projection, which are executed in the same DynamoDB transaction as
+// the OCC version check.
+type MessageHandler interface {
+	// Configure produces a configuration for this handler by calling
+	// methods on the configurer, c.
+	Configure(c dogma.ProjectionConfigurer)
+
+	// HandleEvent returns the transact items needed to update the
+	// projection to reflect the occurrence of an event.
+	HandleEvent(
+		ctx context.Context,
+		s dogma.ProjectionEventScope,
+		m dogma.Message,
+	) ([]types.TransactWriteItem, error)
+
+	// TimeoutHint returns a duration that is suitable for computing a
+	// deadline for the handling of the given message by this handler.
+	TimeoutHint(m dogma.Message) time.Duration
+
+	// Compact reduces the size of the projection's data.
+	Compact(ctx context.Context, s dogma.ProjectionCompactScope) error
+}
+
+// New returns a dogma.ProjectionMessageHandler that delegates to h, using
+// the DynamoDB table named table, accessed via client, to track the OCC
+// resource versions used to make event handling idempotent.
+func New(
+	h MessageHandler,
+	client *dynamodb.Client,
+	table string,
+	options ...HandlerOption,
+) dogma.ProjectionMessageHandler {
+	d := &decorators{}
+	for _, opt := range options {
+		opt.applyOptionToAdaptor(d)
+	}
+
+	return &adaptor{
+		MessageHandler: h,
+		client:         client,
+		table:          table,
+		key:            identity.Key(h),
+		decorators:     d,
+	}
+}
+
+type adaptor struct {
+	MessageHandler
+
+	client     *dynamodb.Client
+	table      string
+	key        string
+	decorators *decorators
+}
+
+func (a *adaptor) HandleEvent(
+	ctx context.Context,
+	r, c, n []byte,
+	s dogma.ProjectionEventScope,
+	m dogma.Message,
+) (bool, error) {
+	items, err := a.MessageHandler.HandleEvent(ctx, s, m)
+	if err != nil {
+		return false, err
+	}
+
+	in := &dynamodb.TransactWriteItemsInput{
+		TransactItems: append(
+			[]types.TransactWriteItem{a.versionCheckItem(r, c, n)},
+			items...,
+		),
+	}
+
+	var reqOpts []func(*dynamodb.Options)
+	if a.decorators.decorateTransactWriteItems != nil {
+		reqOpts = a.decorators.decorateTransactWriteItems(in)
+	}
+
+	_, err = a.client.TransactWriteItems(ctx, in, reqOpts...)
+	if err == nil {
+		return true, nil
+	}
+
+	var canceled *types.TransactionCanceledException
+	if !errors.As(err, &canceled) || !isVersionCheckFailure(canceled.CancellationReasons) {
+		return false, err
+	}
+
+	if !a.decorators.returnVersionOnConflict {
+		return false, nil
+	}
+
+	occErr, parseErr := occConflictError(canceled.CancellationReasons)
+	if parseErr != nil {
+		return false, parseErr
+	}
+
+	return false, occErr
+}
+
+// isVersionCheckFailure returns true if reasons indicates that the OCC
+// version-check item (always the first item in the transaction) failed its
+// condition check.
+func isVersionCheckFailure(reasons []types.CancellationReason) bool {
+	return len(reasons) > 0 &&
+		aws.ToString(reasons[0].Code) == "ConditionalCheckFailed"
+}
+
+// versionCheckItem builds the TransactWriteItem that enforces the OCC
+// condition that resource r's current version, as tracked by this handler,
+// is equal to c, updating it to n as part of the same transaction.
+func (a *adaptor) versionCheckItem(r, c, n []byte) types.TransactWriteItem {
+	key := map[string]types.AttributeValue{
+		"handler":  &types.AttributeValueMemberB{Value: []byte(a.key)},
+		"resource": &types.AttributeValueMemberB{Value: r},
+	}
+
+	var rv types.ReturnValuesOnConditionCheckFailure
+	if a.decorators.returnVersionOnConflict {
+		rv = types.ReturnValuesOnConditionCheckFailureAllOld
+	}
+
+	if len(n) == 0 {
+		return types.TransactWriteItem{
+			Delete: &types.Delete{
+				TableName:           aws.String(a.table),
+				Key:                 key,
+				ConditionExpression: aws.String("version = :c"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":c": &types.AttributeValueMemberB{Value: c},
+				},
+				ReturnValuesOnConditionCheckFailure: rv,
+			},
+		}
+	}
+
+	var cond string
+	values := map[string]types.AttributeValue{}
+
+	if len(c) == 0 {
+		cond = "attribute_not_exists(handler)"
+	} else {
+		cond = "version = :c"
+		values[":c"] = &types.AttributeValueMemberB{Value: c}
+	}
+
+	return types.TransactWriteItem{
+		Put: &types.Put{
+			TableName: aws.String(a.table),
+			Item: map[string]types.AttributeValue{
+				"handler":  key["handler"],
+				"resource": key["resource"],
+				"version":  &types.AttributeValueMemberB{Value: n},
+			},
+			ConditionExpression:                 aws.String(cond),
+			ExpressionAttributeValues:           values,
+			ReturnValuesOnConditionCheckFailure: rv,
+		},
+	}
+}
+
+func (a *adaptor) ResourceVersion(ctx context.Context, r []byte) ([]byte, error) {
+	out, err := a.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(a.table),
+		Key: map[string]types.AttributeValue{
+			"handler":  &types.AttributeValueMemberB{Value: []byte(a.key)},
+			"resource": &types.AttributeValueMemberB{Value: r},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if out.Item == nil {
+		return nil, nil
+	}
+
+	v, ok := out.Item["version"].(*types.AttributeValueMemberB)
+	if !ok {
+		return nil, nil
+	}
+
+	return v.Value, nil
+}
+
+func (a *adaptor) CloseResource(ctx context.Context, r []byte) error {
+	_, err := a.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(a.table),
+		Key: map[string]types.AttributeValue{
+			"handler":  &types.AttributeValueMemberB{Value: []byte(a.key)},
+			"resource": &types.AttributeValueMemberB{Value: r},
+		},
+	})
+
+	return err
+}
@@ -0,0 +1,13 @@
+package dynamoprojection
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestDynamoProjection(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "dynamoprojection Suite")
+}
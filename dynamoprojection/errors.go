@@ -0,0 +1,61 @@
+package dynamoprojection
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// OCCConflictError indicates that an event could not be applied because the
+// resource's current version did not match the version expected by the
+// caller (an "OCC conflict").
+//
+// It is only returned when the WithReturnVersionOnConflict() option is in
+// effect; otherwise a conflict is reported without an error.
+//
+// StoredVersion contains the version that was actually stored for the
+// resource at the time of the conflict. It is nil if DynamoDB returned no
+// stored item for the resource.
+type OCCConflictError struct {
+	StoredVersion []byte
+}
+
+func (e OCCConflictError) Error() string {
+	return "optimistic concurrency control conflict"
+}
+
+// AsOCCConflictError returns the OCCConflictError wrapped by err, if any.
+func AsOCCConflictError(err error) (OCCConflictError, bool) {
+	var occErr OCCConflictError
+	ok := errors.As(err, &occErr)
+	return occErr, ok
+}
+
+// occConflictError builds an OCCConflictError from the cancellation reasons
+// of a failed TransactWriteItems call.
+//
+// reasons[0] corresponds to the version-check item, which is always the
+// first item in the transaction (see WithDecorateTransactWriteItems). Its
+// Item field is only populated when ReturnValuesOnConditionCheckFailure was
+// set to ALL_OLD, which WithReturnVersionOnConflict() arranges for.
+func occConflictError(reasons []types.CancellationReason) (OCCConflictError, error) {
+	var occErr OCCConflictError
+
+	if len(reasons) == 0 || reasons[0].Item == nil {
+		return occErr, nil
+	}
+
+	var stored struct {
+		Version []byte `dynamodbav:"version"`
+	}
+
+	if err := attributevalue.UnmarshalMap(reasons[0].Item, &stored); err != nil {
+		return occErr, fmt.Errorf("unable to unmarshal conflicting OCC version: %w", err)
+	}
+
+	occErr.StoredVersion = stored.Version
+
+	return occErr, nil
+}
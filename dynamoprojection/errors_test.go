@@ -0,0 +1,80 @@
+package dynamoprojection
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("func occConflictError()", func() {
+	It("returns a zero-value error when there are no cancellation reasons", func() {
+		occErr, err := occConflictError(nil)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(occErr.StoredVersion).To(BeNil())
+	})
+
+	It("returns a zero-value error when the version-check item was not returned", func() {
+		// ReturnValuesOnConditionCheckFailure was not set to ALL_OLD, so
+		// reasons[0].Item is nil, as it would be without
+		// WithReturnVersionOnConflict().
+		occErr, err := occConflictError([]types.CancellationReason{
+			{Code: aws.String("ConditionalCheckFailed")},
+		})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(occErr.StoredVersion).To(BeNil())
+	})
+
+	It("extracts the conflicting version from the version-check item", func() {
+		occErr, err := occConflictError([]types.CancellationReason{
+			{
+				Code: aws.String("ConditionalCheckFailed"),
+				Item: map[string]types.AttributeValue{
+					"handler":  &types.AttributeValueMemberB{Value: []byte("<handler>")},
+					"resource": &types.AttributeValueMemberB{Value: []byte("<resource>")},
+					"version":  &types.AttributeValueMemberB{Value: []byte("<stored-version>")},
+				},
+			},
+		})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(occErr.StoredVersion).To(Equal([]byte("<stored-version>")))
+	})
+})
+
+var _ = Describe("func AsOCCConflictError()", func() {
+	It("returns true when err is an OCCConflictError", func() {
+		var err error = OCCConflictError{StoredVersion: []byte("<v>")}
+
+		occErr, ok := AsOCCConflictError(err)
+		Expect(ok).To(BeTrue())
+		Expect(occErr.StoredVersion).To(Equal([]byte("<v>")))
+	})
+
+	It("returns false for unrelated errors", func() {
+		_, ok := AsOCCConflictError(errNotOCC{})
+		Expect(ok).To(BeFalse())
+	})
+})
+
+type errNotOCC struct{}
+
+func (errNotOCC) Error() string { return "<not an OCC conflict>" }
+
+var _ = Describe("func isVersionCheckFailure()", func() {
+	It("returns false when there are no cancellation reasons", func() {
+		Expect(isVersionCheckFailure(nil)).To(BeFalse())
+	})
+
+	It("returns true when the first reason is a conditional check failure", func() {
+		Expect(isVersionCheckFailure([]types.CancellationReason{
+			{Code: aws.String("ConditionalCheckFailed")},
+		})).To(BeTrue())
+	})
+
+	It("returns false when the first reason is some other failure", func() {
+		Expect(isVersionCheckFailure([]types.CancellationReason{
+			{Code: aws.String("ValidationError")},
+		})).To(BeFalse())
+	})
+})
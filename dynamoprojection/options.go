@@ -28,6 +28,8 @@ type decorators struct {
 	decorateTransactWriteItems func(*dynamodb.TransactWriteItemsInput) []func(*dynamodb.Options)
 	decorateCreateTableItem    func(*dynamodb.CreateTableInput) []func(*dynamodb.Options)
 	decorateDeleteTableItem    func(*dynamodb.DeleteTableInput) []func(*dynamodb.Options)
+	returnVersionOnConflict    bool
+	listResourcesSegments      int32
 }
 
 type options struct {
@@ -129,6 +131,39 @@ func WithDecorateTransactWriteItems(
 	}
 }
 
+// WithReturnVersionOnConflict configures the handler to request the item
+// that caused an OCC conflict when an event can not be applied.
+//
+// Normally, when the TransactWriteItems call used to update a projection's
+// resource version fails because the OCC condition check did not match, the
+// caller only learns that a conflict occurred, and must perform a follow-up
+// GetItem call to discover the resource's current version.
+//
+// When this option is used, the version-check item's
+// ReturnValuesOnConditionCheckFailure is set to ALL_OLD, and the conflicting
+// version is parsed from the resulting TransactionCanceledException and
+// returned as an OCCConflictError, avoiding the extra round-trip.
+func WithReturnVersionOnConflict() HandlerOption {
+	return &options{
+		applyOptionToAdaptorFunc: func(d *decorators) {
+			d.returnVersionOnConflict = true
+		},
+	}
+}
+
+// WithListResourcesSegments configures a ResourceRepository's ListResources
+// method to scan the underlying table as n segments instead of the default
+// of 1. Segments are scanned one after another, not in parallel; see the
+// DynamoDB documentation for the Scan operation's Segment and
+// TotalSegments parameters.
+func WithListResourcesSegments(n int32) ResourceRepositoryOption {
+	return &options{
+		applyResourceRepositoryOptionFunc: func(d *decorators) {
+			d.listResourcesSegments = n
+		},
+	}
+}
+
 // WithDecorateCreateTable adds a decorator for CreateTable operation. The
 // decorator can modify the passed CreateTableInput structure and return
 // a slice of request.Option to alter the request prior to its execution.
@@ -0,0 +1,274 @@
+package dynamoprojection
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/dezlitz/projectionkit/internal/identity"
+	"github.com/dogmatiq/dogma"
+)
+
+// dynamoBatchGetLimit is the maximum number of items DynamoDB allows per
+// BatchGetItem request.
+const dynamoBatchGetLimit = 100
+
+// dynamoBatchWriteLimit is the maximum number of items DynamoDB allows per
+// BatchWriteItem request.
+const dynamoBatchWriteLimit = 25
+
+// ResourceRepository provides access to the OCC resource versions tracked
+// on behalf of a specific handler, independently of event handling. It is
+// intended for use by administration tooling that needs to inspect or
+// repair a projection's resource versions, or build status dashboards
+// cheaply.
+type ResourceRepository struct {
+	client   *dynamodb.Client
+	table    string
+	key      string
+	segments int32
+}
+
+// NewResourceRepository returns a ResourceRepository that manipulates the
+// resource versions tracked on behalf of h, within the DynamoDB table
+// named table.
+//
+// Of the given options, only WithListResourcesSegments affects the
+// repository; decorators set by WithDecorateGetItem, WithDecoratePutItem
+// and WithDecorateDeleteItem are not applied to its requests.
+func NewResourceRepository(
+	client *dynamodb.Client,
+	table string,
+	h dogma.ProjectionMessageHandler,
+	options ...ResourceRepositoryOption,
+) *ResourceRepository {
+	d := &decorators{}
+	for _, opt := range options {
+		opt.applyResourceRepositoryOption(d)
+	}
+
+	segments := d.listResourcesSegments
+	if segments < 1 {
+		segments = 1
+	}
+
+	return &ResourceRepository{
+		client:   client,
+		table:    table,
+		key:      identity.Key(h),
+		segments: segments,
+	}
+}
+
+func (rr *ResourceRepository) itemKey(r []byte) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"handler":  &types.AttributeValueMemberB{Value: []byte(rr.key)},
+		"resource": &types.AttributeValueMemberB{Value: r},
+	}
+}
+
+// ResourceVersion returns the current version of the resource r.
+func (rr *ResourceRepository) ResourceVersion(ctx context.Context, r []byte) ([]byte, error) {
+	out, err := rr.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(rr.table),
+		Key:            rr.itemKey(r),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if out.Item == nil {
+		return nil, nil
+	}
+
+	v, ok := out.Item["version"].(*types.AttributeValueMemberB)
+	if !ok {
+		return nil, nil
+	}
+
+	return v.Value, nil
+}
+
+// StoreResourceVersion unconditionally sets the version of the resource r
+// to v, bypassing the OCC check performed by HandleEvent.
+func (rr *ResourceRepository) StoreResourceVersion(ctx context.Context, r, v []byte) error {
+	_, err := rr.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(rr.table),
+		Item: map[string]types.AttributeValue{
+			"handler":  &types.AttributeValueMemberB{Value: []byte(rr.key)},
+			"resource": &types.AttributeValueMemberB{Value: r},
+			"version":  &types.AttributeValueMemberB{Value: v},
+		},
+	})
+
+	return err
+}
+
+// DeleteResource removes all information about the resource r.
+func (rr *ResourceRepository) DeleteResource(ctx context.Context, r []byte) error {
+	_, err := rr.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(rr.table),
+		Key:       rr.itemKey(r),
+	})
+
+	return err
+}
+
+// QueryVersions returns the current versions of the given resources, keyed
+// by resource. Resources with no recorded version are omitted from the
+// result.
+//
+// It uses BatchGetItem, chunking resources into groups of at most 100
+// items as required by DynamoDB, and retrying any keys DynamoDB reports as
+// unprocessed.
+func (rr *ResourceRepository) QueryVersions(ctx context.Context, resources [][]byte) (map[string][]byte, error) {
+	versions := map[string][]byte{}
+
+	for len(resources) > 0 {
+		n := dynamoBatchGetLimit
+		if n > len(resources) {
+			n = len(resources)
+		}
+		chunk := resources[:n]
+		resources = resources[n:]
+
+		keys := make([]map[string]types.AttributeValue, len(chunk))
+		for i, r := range chunk {
+			keys[i] = rr.itemKey(r)
+		}
+
+		items := map[string]types.KeysAndAttributes{rr.table: {Keys: keys}}
+
+		for len(items) > 0 {
+			out, err := rr.client.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{
+				RequestItems: items,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			for _, item := range out.Responses[rr.table] {
+				r, ok := item["resource"].(*types.AttributeValueMemberB)
+				if !ok {
+					continue
+				}
+
+				v, ok := item["version"].(*types.AttributeValueMemberB)
+				if !ok {
+					continue
+				}
+
+				versions[string(r.Value)] = v.Value
+			}
+
+			items = out.UnprocessedKeys
+		}
+	}
+
+	return versions, nil
+}
+
+// DeleteResources removes all information about the given resources.
+//
+// It uses BatchWriteItem, chunking resources into groups of at most 25
+// items as required by DynamoDB, and retrying any items DynamoDB reports as
+// unprocessed.
+func (rr *ResourceRepository) DeleteResources(ctx context.Context, resources [][]byte) error {
+	for len(resources) > 0 {
+		n := dynamoBatchWriteLimit
+		if n > len(resources) {
+			n = len(resources)
+		}
+		chunk := resources[:n]
+		resources = resources[n:]
+
+		reqs := make([]types.WriteRequest, len(chunk))
+		for i, r := range chunk {
+			reqs[i] = types.WriteRequest{
+				DeleteRequest: &types.DeleteRequest{Key: rr.itemKey(r)},
+			}
+		}
+
+		items := map[string][]types.WriteRequest{rr.table: reqs}
+
+		for len(items) > 0 {
+			out, err := rr.client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+				RequestItems: items,
+			})
+			if err != nil {
+				return err
+			}
+
+			items = out.UnprocessedItems
+		}
+	}
+
+	return nil
+}
+
+// ListResources calls fn once for each resource tracked on behalf of the
+// repository's handler, passing the resource and its current version.
+//
+// It uses Scan to page through the table, keeping at most one page of
+// items in memory at a time. By default the whole table is scanned as a
+// single segment; use WithListResourcesSegments to have it walk multiple
+// equally-sized segments instead, one after another.
+//
+// Iteration stops, and ListResources returns fn's error, as soon as fn
+// returns a non-nil error.
+func (rr *ResourceRepository) ListResources(
+	ctx context.Context,
+	fn func(resource, version []byte) error,
+) error {
+	for segment := int32(0); segment < rr.segments; segment++ {
+		var startKey map[string]types.AttributeValue
+
+		for {
+			out, err := rr.client.Scan(ctx, &dynamodb.ScanInput{
+				TableName:         aws.String(rr.table),
+				Segment:           aws.Int32(segment),
+				TotalSegments:     aws.Int32(rr.segments),
+				FilterExpression:  aws.String("handler = :h"),
+				ExclusiveStartKey: startKey,
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":h": &types.AttributeValueMemberB{Value: []byte(rr.key)},
+				},
+			})
+			if err != nil {
+				return err
+			}
+
+			for _, item := range out.Items {
+				r, ok := item["resource"].(*types.AttributeValueMemberB)
+				if !ok {
+					continue
+				}
+
+				v, ok := item["version"].(*types.AttributeValueMemberB)
+				if !ok {
+					continue
+				}
+
+				if err := fn(r.Value, v.Value); err != nil {
+					return err
+				}
+			}
+
+			startKey = out.LastEvaluatedKey
+			if len(startKey) == 0 {
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// Close closes the repository. It does not close the underlying DynamoDB
+// client.
+func (rr *ResourceRepository) Close() error {
+	return nil
+}
@@ -0,0 +1,86 @@
+package sqlprojection
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Driver is an interface for database-specific logic used by the
+// projection handler and resource repository to store projection OCC
+// resource versions.
+type Driver interface {
+	// CreateSchema creates the schema elements required to store
+	// projection OCC resource versions.
+	//
+	// It is equivalent to applying the first of this driver's Migrations
+	// to an empty database, and is retained for applications that do not
+	// need fine-grained control over schema evolution.
+	CreateSchema(ctx context.Context, db *sql.DB) error
+
+	// DropSchema removes the schema elements created by CreateSchema.
+	DropSchema(ctx context.Context, db *sql.DB) error
+
+	// IsCompatibleWith returns true if this driver can be used with db.
+	IsCompatibleWith(db *sql.DB) bool
+
+	// StoreVersion unconditionally sets the version of resource r, as
+	// tracked by handler h, to v.
+	StoreVersion(ctx context.Context, db *sql.DB, h string, r, v []byte) error
+
+	// UpdateVersion updates the version of resource r, as tracked by
+	// handler h, from c to n.
+	//
+	// It returns false if the resource's current version is not equal to
+	// c.
+	UpdateVersion(ctx context.Context, tx *sql.Tx, h string, r, c, n []byte) (bool, error)
+
+	// QueryVersion returns the current version of resource r, as tracked
+	// by handler h.
+	QueryVersion(ctx context.Context, db *sql.DB, h string, r []byte) ([]byte, error)
+
+	// DeleteResource removes all information about resource r, as
+	// tracked by handler h.
+	DeleteResource(ctx context.Context, db *sql.DB, h string, r []byte) error
+
+	// QueryVersions returns the current versions of the given resources,
+	// as tracked by handler h, keyed by resource.
+	//
+	// Resources with no recorded version are omitted from the result; it
+	// is not an error for any or all of resources to be unrecognized.
+	QueryVersions(ctx context.Context, db *sql.DB, h string, resources [][]byte) (map[string][]byte, error)
+
+	// DeleteResources removes all information about the given resources,
+	// as tracked by handler h.
+	DeleteResources(ctx context.Context, db *sql.DB, h string, resources [][]byte) error
+
+	// ListResources calls fn once for each resource tracked by handler h,
+	// passing the resource and its current version. It streams results
+	// using keyset pagination so that callers can enumerate a handler's
+	// resources without loading them all into memory at once.
+	//
+	// Iteration stops, and ListResources returns fn's error, as soon as fn
+	// returns a non-nil error.
+	ListResources(ctx context.Context, db *sql.DB, h string, fn func(resource, version []byte) error) error
+
+	// Migrations returns the ordered, built-in set of migrations used to
+	// create and evolve this driver's schema.
+	Migrations() []Migration
+
+	// CreateMigrationsTable creates the bookkeeping table used to record
+	// which of this driver's migrations have been applied, if it does
+	// not already exist.
+	//
+	// Its name, DDL dialect and placeholder style are driver-specific,
+	// since they must match the rest of the driver's schema (for
+	// example, DynamoSQLDriver's PartiQL syntax differs substantially
+	// from PostgresDriver's).
+	CreateMigrationsTable(ctx context.Context, ex Executor) error
+
+	// AppliedMigrations returns the set of this driver's migration
+	// versions that have already been applied.
+	AppliedMigrations(ctx context.Context, ex Executor) (map[int]bool, error)
+
+	// RecordMigration records that this driver's migration identified by
+	// version has been applied.
+	RecordMigration(ctx context.Context, ex Executor, version int) error
+}
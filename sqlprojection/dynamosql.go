@@ -0,0 +1,377 @@
+package sqlprojection
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/btnguyen2k/godynamo"
+)
+
+// occTable is the name of the DynamoDB table used to store OCC resource
+// versions when a projection is backed by DynamoSQLDriver.
+const occTable = `"projection.occ"`
+
+// DynamoSQLDriver is a Driver for DynamoDB, accessed through the standard
+// database/sql interface via PartiQL using the godynamo driver.
+//
+// It is an alternative to the dynamoprojection package for applications
+// that already standardize their database access, connection pooling and
+// transaction handling on *sql.DB, and would rather not take on the
+// separate AWS SDK-based decorator surface that dynamoprojection exposes.
+var DynamoSQLDriver Driver = dynamoSQLDriver{}
+
+type dynamoSQLDriver struct{}
+
+func (d dynamoSQLDriver) CreateSchema(ctx context.Context, db *sql.DB) error {
+	// Unlike PostgresDriver, this must not run within a transaction: the
+	// godynamo driver maps a *sql.Tx onto DynamoDB's TransactWriteItems
+	// API, which accepts only item writes, not the CREATE TABLE
+	// statement migration #1 issues. See DropSchema, below, which runs
+	// directly against db for the same reason.
+	return d.Migrations()[0].Up(ctx, db)
+}
+
+func (dynamoSQLDriver) DropSchema(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(
+		ctx,
+		`DROP TABLE `+occTable,
+	)
+	return err
+}
+
+func (dynamoSQLDriver) IsCompatibleWith(db *sql.DB) bool {
+	_, ok := db.Driver().(*godynamo.Driver)
+	return ok
+}
+
+func (d dynamoSQLDriver) StoreVersion(
+	ctx context.Context,
+	db *sql.DB,
+	h string,
+	r, v []byte,
+) error {
+	_, err := db.ExecContext(
+		ctx,
+		`INSERT INTO `+occTable+` VALUE {'handler': ?, 'resource': ?, 'version': ?}`,
+		h,
+		r,
+		v,
+	)
+
+	// An item with this key may already exist; PartiQL INSERT fails in
+	// that case, so fall back to an unconditional update.
+	if d.isDup(err) {
+		_, err = db.ExecContext(
+			ctx,
+			`UPDATE `+occTable+` SET version = ? WHERE handler = ? AND resource = ?`,
+			v,
+			h,
+			r,
+		)
+	}
+
+	return err
+}
+
+func (d dynamoSQLDriver) UpdateVersion(
+	ctx context.Context,
+	tx *sql.Tx,
+	h string,
+	r, c, n []byte,
+) (bool, error) {
+	if len(c) == 0 {
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO `+occTable+` VALUE {'handler': ?, 'resource': ?, 'version': ?}`,
+			h,
+			r,
+			n,
+		)
+
+		if d.isDup(err) {
+			return false, nil
+		}
+
+		return true, err
+	}
+
+	var (
+		res sql.Result
+		err error
+	)
+
+	if len(n) == 0 {
+		res, err = tx.ExecContext(
+			ctx,
+			`DELETE FROM `+occTable+` WHERE handler = ? AND resource = ? AND version = ?`,
+			h,
+			r,
+			c,
+		)
+	} else {
+		res, err = tx.ExecContext(
+			ctx,
+			`UPDATE `+occTable+` SET version = ? WHERE handler = ? AND resource = ? AND version = ?`,
+			n,
+			h,
+			r,
+			c,
+		)
+	}
+
+	if d.isDup(err) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	count, err := res.RowsAffected()
+	return count != 0, err
+}
+
+func (dynamoSQLDriver) QueryVersion(
+	ctx context.Context,
+	db *sql.DB,
+	h string,
+	r []byte,
+) ([]byte, error) {
+	row := db.QueryRowContext(
+		ctx,
+		`SELECT version FROM `+occTable+` WHERE handler = ? AND resource = ?`,
+		h,
+		r,
+	)
+
+	var v []byte
+	err := row.Scan(&v)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return v, err
+}
+
+func (dynamoSQLDriver) DeleteResource(
+	ctx context.Context,
+	db *sql.DB,
+	h string,
+	r []byte,
+) error {
+	_, err := db.ExecContext(
+		ctx,
+		`DELETE FROM `+occTable+` WHERE handler = ? AND resource = ?`,
+		h,
+		r,
+	)
+
+	return err
+}
+
+func (d dynamoSQLDriver) QueryVersions(
+	ctx context.Context,
+	db *sql.DB,
+	h string,
+	resources [][]byte,
+) (map[string][]byte, error) {
+	// PartiQL for DynamoDB has no equivalent of Postgres' "= ANY(...)",
+	// so resources are fetched with one statement per item rather than
+	// in a single batch.
+	versions := map[string][]byte{}
+
+	for _, r := range resources {
+		v, err := d.QueryVersion(ctx, db, h, r)
+		if err != nil {
+			return nil, err
+		}
+
+		if v != nil {
+			versions[string(r)] = v
+		}
+	}
+
+	return versions, nil
+}
+
+func (d dynamoSQLDriver) DeleteResources(
+	ctx context.Context,
+	db *sql.DB,
+	h string,
+	resources [][]byte,
+) error {
+	for _, r := range resources {
+		if err := d.DeleteResource(ctx, db, h, r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dynamoListResourcesPageSize is the number of rows ListResources reads from
+// each statement before issuing the next one, starting after the last
+// resource read. The statement itself carries no limit.
+const dynamoListResourcesPageSize = 100
+
+func (dynamoSQLDriver) ListResources(
+	ctx context.Context,
+	db *sql.DB,
+	h string,
+	fn func(resource, version []byte) error,
+) error {
+	// The resource range key sorts lexicographically, so "resource > ?"
+	// can be used as a keyset cursor in exactly the same way a Query
+	// against the underlying table would use ExclusiveStartKey.
+	after := []byte{}
+
+	for {
+		rows, err := db.QueryContext(
+			ctx,
+			`SELECT resource, version FROM `+occTable+`
+			WHERE handler = ? AND resource > ?`,
+			h,
+			after,
+		)
+		if err != nil {
+			return err
+		}
+
+		var n int
+		for n < dynamoListResourcesPageSize && rows.Next() {
+			var r, v []byte
+			if err := rows.Scan(&r, &v); err != nil {
+				rows.Close()
+				return err
+			}
+
+			if err := fn(r, v); err != nil {
+				rows.Close()
+				return err
+			}
+
+			after = r
+			n++
+		}
+
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}
+
+		if n < dynamoListResourcesPageSize {
+			return nil
+		}
+	}
+}
+
+// isDup returns true if err indicates that a conditional PartiQL statement
+// (an INSERT, UPDATE or DELETE guarded by an implicit or explicit condition)
+// did not meet its condition, or that an INSERT targeted a primary key that
+// already exists.
+func (dynamoSQLDriver) isDup(err error) bool {
+	var ccf *types.ConditionalCheckFailedException
+	if errors.As(err, &ccf) {
+		return true
+	}
+
+	var dup *types.DuplicateItemException
+	if errors.As(err, &dup) {
+		return true
+	}
+
+	// godynamo does not always preserve the typed AWS SDK error across
+	// the database/sql boundary, so fall back to matching on the
+	// underlying DynamoDB exception name.
+	return err != nil && (strings.Contains(err.Error(), "ConditionalCheckFailedException") ||
+		strings.Contains(err.Error(), "DuplicateItemException"))
+}
+
+// nonTransactionalMigrations marks DynamoSQLDriver as a nonTransactionalDriver:
+// its CREATE TABLE and SELECT statements cannot run within the *sql.Tx
+// godynamo maps onto DynamoDB's TransactWriteItems API, so Migrate() runs
+// its migrations and bookkeeping directly against the *sql.DB instead of
+// within a shared transaction, and does not attempt to lock it.
+func (dynamoSQLDriver) nonTransactionalMigrations() {}
+
+// Migrations returns the built-in migrations that create and evolve the
+// schema used by DynamoSQLDriver.
+func (dynamoSQLDriver) Migrations() []Migration {
+	return []Migration{
+		migration{
+			version: 1,
+			up: func(ctx context.Context, ex Executor) error {
+				_, err := ex.ExecContext(
+					ctx,
+					`CREATE TABLE `+occTable+` (
+						handler  VARCHAR HASH KEY,
+						resource VARCHAR RANGE KEY
+					) WITH PK=handler:VARCHAR WITH SK=resource:VARCHAR`,
+				)
+				return err
+			},
+			down: func(ctx context.Context, ex Executor) error {
+				_, err := ex.ExecContext(ctx, `DROP TABLE `+occTable)
+				return err
+			},
+		},
+	}
+}
+
+// migrationsTable is the name of the DynamoDB table used to record applied
+// migrations when a projection is backed by DynamoSQLDriver.
+const migrationsTable = `"projection.schema_migrations"`
+
+// dynamoSQLDriverName is the value recorded in the driver attribute of the
+// migrations table for migrations applied by DynamoSQLDriver.
+const dynamoSQLDriverName = "dynamosql"
+
+func (dynamoSQLDriver) CreateMigrationsTable(ctx context.Context, ex Executor) error {
+	_, err := ex.ExecContext(
+		ctx,
+		`CREATE TABLE IF NOT EXISTS `+migrationsTable+` (
+			driver  VARCHAR HASH KEY,
+			version NUMBER RANGE KEY
+		) WITH PK=driver:VARCHAR WITH SK=version:NUMBER`,
+	)
+	return err
+}
+
+func (dynamoSQLDriver) AppliedMigrations(ctx context.Context, ex Executor) (map[int]bool, error) {
+	rows, err := ex.QueryContext(
+		ctx,
+		`SELECT version FROM `+migrationsTable+` WHERE driver = ?`,
+		dynamoSQLDriverName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]bool{}
+
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		applied[v] = true
+	}
+
+	return applied, rows.Err()
+}
+
+func (dynamoSQLDriver) RecordMigration(ctx context.Context, ex Executor, version int) error {
+	_, err := ex.ExecContext(
+		ctx,
+		`INSERT INTO `+migrationsTable+` VALUE {'driver': ?, 'version': ?}`,
+		dynamoSQLDriverName,
+		version,
+	)
+	return err
+}
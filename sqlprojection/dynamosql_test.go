@@ -0,0 +1,48 @@
+package sqlprojection
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("func (dynamoSQLDriver) isDup()", func() {
+	d := dynamoSQLDriver{}
+
+	It("returns false for a nil error", func() {
+		Expect(d.isDup(nil)).To(BeFalse())
+	})
+
+	It("returns true for a typed ConditionalCheckFailedException", func() {
+		Expect(d.isDup(&types.ConditionalCheckFailedException{})).To(BeTrue())
+	})
+
+	It("returns true for a wrapped ConditionalCheckFailedException", func() {
+		err := fmt.Errorf("exec failed: %w", &types.ConditionalCheckFailedException{})
+		Expect(d.isDup(err)).To(BeTrue())
+	})
+
+	It("returns true when godynamo surfaces the exception only by name", func() {
+		// godynamo does not always preserve the typed AWS SDK error across
+		// the database/sql boundary.
+		err := errors.New(`ExecuteStatement: ConditionalCheckFailedException: the conditional request failed`)
+		Expect(d.isDup(err)).To(BeTrue())
+	})
+
+	It("returns true for a typed DuplicateItemException", func() {
+		Expect(d.isDup(&types.DuplicateItemException{})).To(BeTrue())
+	})
+
+	It("returns true when godynamo surfaces a duplicate INSERT only by name", func() {
+		err := errors.New(`ExecuteStatement: DuplicateItemException: the item already exists`)
+		Expect(d.isDup(err)).To(BeTrue())
+	})
+
+	It("returns false for unrelated errors", func() {
+		Expect(d.isDup(errors.New("connection refused"))).To(BeFalse())
+	})
+})
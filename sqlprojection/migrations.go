@@ -0,0 +1,151 @@
+package sqlprojection
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Executor is satisfied by both *sql.DB and *sql.Tx. Migrations and a
+// Driver's migration bookkeeping operate through an Executor rather than
+// directly through *sql.Tx, because not every database a Driver targets
+// supports transactional DDL (for example, DynamoSQLDriver's CREATE TABLE
+// statements, issued via godynamo, must run outside of a transaction).
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Migration is a single, versioned change to the schema used by a
+// sqlprojection Driver.
+type Migration interface {
+	// Version returns the migration's ordinal position within its
+	// driver's migration sequence. Versions start at 1 and must be
+	// contiguous.
+	Version() int
+
+	// Up applies the migration using ex.
+	Up(ctx context.Context, ex Executor) error
+
+	// Down reverts the migration using ex.
+	Down(ctx context.Context, ex Executor) error
+}
+
+// migration is a Migration implemented in terms of a pair of functions. It
+// is used by built-in Driver implementations to describe their migrations
+// without each needing its own named type.
+type migration struct {
+	version int
+	up      func(ctx context.Context, ex Executor) error
+	down    func(ctx context.Context, ex Executor) error
+}
+
+func (m migration) Version() int { return m.version }
+
+func (m migration) Up(ctx context.Context, ex Executor) error {
+	return m.up(ctx, ex)
+}
+
+func (m migration) Down(ctx context.Context, ex Executor) error {
+	return m.down(ctx, ex)
+}
+
+// migrationLocker is implemented by drivers that require a database-level
+// lock to be held for the duration of Migrate(), preventing multiple
+// processes from applying migrations to the same database concurrently.
+//
+// It is only consulted for drivers that run their migrations within a
+// transaction; see nonTransactionalDriver.
+type migrationLocker interface {
+	lockMigrations(ctx context.Context, tx *sql.Tx) error
+}
+
+// nonTransactionalDriver is implemented by drivers whose underlying
+// database does not support transactional DDL, and so cannot run
+// CreateMigrationsTable, AppliedMigrations, RecordMigration or a
+// migration's Up/Down within a single shared *sql.Tx.
+//
+// DynamoSQLDriver implements this: its godynamo driver maps a *sql.Tx onto
+// DynamoDB's TransactWriteItems, which accepts only item writes, not the
+// CREATE TABLE and SELECT statements its migration bookkeeping requires.
+//
+// Migrate() runs such a driver's bookkeeping and migrations directly
+// against db, each as its own independent statement, rather than within a
+// single all-or-nothing transaction, and does not consult migrationLocker.
+type nonTransactionalDriver interface {
+	nonTransactionalMigrations()
+}
+
+// Migrate applies any of d's migrations that have not yet been applied to
+// db, recording each applied version in d's schema_migrations bookkeeping
+// table.
+//
+// Each Driver is responsible for its own schema_migrations table, via
+// CreateMigrationsTable, AppliedMigrations and RecordMigration, since its
+// name, DDL dialect and bind placeholder style may differ from other
+// drivers sharing the sqlprojection package (for example, DynamoSQLDriver's
+// PartiQL syntax differs substantially from PostgresDriver's).
+//
+// If d does not implement nonTransactionalDriver, its migrations and
+// bookkeeping are applied within a single transaction, so that a failure
+// partway through leaves db unchanged. If d also implements
+// migrationLocker, its lock is acquired for the lifetime of that
+// transaction so that concurrent calls to Migrate(), whether from this
+// process or another, do not race.
+//
+// If d implements nonTransactionalDriver, its migrations and bookkeeping
+// are instead applied directly against db, one statement at a time,
+// without any surrounding transaction or lock.
+func Migrate(ctx context.Context, db *sql.DB, d Driver) error {
+	if _, ok := d.(nonTransactionalDriver); ok {
+		return applyMigrations(ctx, db, d)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if locker, ok := d.(migrationLocker); ok {
+		if err := locker.lockMigrations(ctx, tx); err != nil {
+			return fmt.Errorf("unable to acquire migration lock: %w", err)
+		}
+	}
+
+	if err := applyMigrations(ctx, tx, d); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// applyMigrations applies d's pending migrations using ex, recording each
+// as it is applied.
+func applyMigrations(ctx context.Context, ex Executor, d Driver) error {
+	if err := d.CreateMigrationsTable(ctx, ex); err != nil {
+		return fmt.Errorf("unable to create schema_migrations table: %w", err)
+	}
+
+	applied, err := d.AppliedMigrations(ctx, ex)
+	if err != nil {
+		return fmt.Errorf("unable to query applied migrations: %w", err)
+	}
+
+	for _, m := range d.Migrations() {
+		if applied[m.Version()] {
+			continue
+		}
+
+		if err := m.Up(ctx, ex); err != nil {
+			return fmt.Errorf("unable to apply migration %d: %w", m.Version(), err)
+		}
+
+		if err := d.RecordMigration(ctx, ex, m.Version()); err != nil {
+			return fmt.Errorf("unable to record migration %d: %w", m.Version(), err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,326 @@
+package sqlprojection
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakeConn/fakeTx/fakeSQLDriver provide just enough of the database/sql
+// driver interface for a *sql.DB to open transactions. The migrations
+// tested here never issue any SQL themselves, so no query/exec support is
+// required.
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return nil, sql.ErrConnDone }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+// recordingConn/recordingTx/recordingStmt extend fakeConn/fakeTx with just
+// enough ExecContext support to capture the literal DDL issued by a
+// Migration's Up()/Down(), so that tests can assert on the actual SQL a
+// built-in Driver emits rather than on a fakeDriver stand-in.
+type recordingConn struct {
+	fakeConn
+	execs *[]string
+}
+
+func (c recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return recordingStmt{query: query, execs: c.execs}, nil
+}
+
+func (c recordingConn) Begin() (driver.Tx, error) { return recordingTx{}, nil }
+
+type recordingTx struct{ fakeTx }
+
+type recordingStmt struct {
+	query string
+	execs *[]string
+}
+
+func (s recordingStmt) Close() error  { return nil }
+func (s recordingStmt) NumInput() int { return -1 }
+
+func (s recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	*s.execs = append(*s.execs, s.query)
+	return driver.RowsAffected(0), nil
+}
+
+func (s recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, sql.ErrNoRows
+}
+
+type fakeSQLDriver struct{}
+
+func (fakeSQLDriver) Open(name string) (driver.Conn, error) { return fakeConn{}, nil }
+
+// recordingExecs maps a DSN to the slice of statements executed against it,
+// so each sql.Open("sqlprojection-recording", dsn) in a test gets its own
+// independent recording.
+var recordingExecs = map[string]*[]string{}
+
+type recordingSQLDriver struct{}
+
+func (recordingSQLDriver) Open(name string) (driver.Conn, error) {
+	execs, ok := recordingExecs[name]
+	if !ok {
+		execs = &[]string{}
+		recordingExecs[name] = execs
+	}
+	return recordingConn{execs: execs}, nil
+}
+
+// noBeginConn executes statements the same way recordingConn does, but
+// fails Begin(), so that a test can assert a code path never opens a
+// transaction.
+type noBeginConn struct{ recordingConn }
+
+func (noBeginConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("BeginTx should not be called for a nonTransactionalDriver")
+}
+
+type noBeginSQLDriver struct{}
+
+func (noBeginSQLDriver) Open(name string) (driver.Conn, error) {
+	return noBeginConn{recordingConn{execs: &[]string{}}}, nil
+}
+
+func init() {
+	sql.Register("sqlprojection-fake", fakeSQLDriver{})
+	sql.Register("sqlprojection-recording", recordingSQLDriver{})
+	sql.Register("sqlprojection-no-begin", noBeginSQLDriver{})
+}
+
+// recordingMigration is a Migration that counts how many times Up() is
+// called, so that tests can assert a migration is not re-applied once it
+// has been recorded.
+type recordingMigration struct {
+	version int
+	upCalls *int
+}
+
+func (m recordingMigration) Version() int { return m.version }
+
+func (m recordingMigration) Up(ctx context.Context, ex Executor) error {
+	*m.upCalls++
+	return nil
+}
+
+func (m recordingMigration) Down(ctx context.Context, ex Executor) error {
+	return nil
+}
+
+// fakeDriver is a minimal Driver implementation whose schema_migrations
+// bookkeeping is an in-memory map, used to exercise Migrate() without a
+// real database connection.
+type fakeDriver struct {
+	migrations []Migration
+	applied    map[int]bool
+}
+
+func (d *fakeDriver) CreateSchema(ctx context.Context, db *sql.DB) error { return nil }
+func (d *fakeDriver) DropSchema(ctx context.Context, db *sql.DB) error   { return nil }
+func (d *fakeDriver) IsCompatibleWith(db *sql.DB) bool                   { return true }
+
+func (d *fakeDriver) StoreVersion(ctx context.Context, db *sql.DB, h string, r, v []byte) error {
+	return nil
+}
+
+func (d *fakeDriver) UpdateVersion(ctx context.Context, tx *sql.Tx, h string, r, c, n []byte) (bool, error) {
+	return true, nil
+}
+
+func (d *fakeDriver) QueryVersion(ctx context.Context, db *sql.DB, h string, r []byte) ([]byte, error) {
+	return nil, nil
+}
+
+func (d *fakeDriver) DeleteResource(ctx context.Context, db *sql.DB, h string, r []byte) error {
+	return nil
+}
+
+func (d *fakeDriver) QueryVersions(ctx context.Context, db *sql.DB, h string, resources [][]byte) (map[string][]byte, error) {
+	return nil, nil
+}
+
+func (d *fakeDriver) DeleteResources(ctx context.Context, db *sql.DB, h string, resources [][]byte) error {
+	return nil
+}
+
+func (d *fakeDriver) ListResources(ctx context.Context, db *sql.DB, h string, fn func(resource, version []byte) error) error {
+	return nil
+}
+
+func (d *fakeDriver) Migrations() []Migration { return d.migrations }
+
+func (d *fakeDriver) CreateMigrationsTable(ctx context.Context, ex Executor) error { return nil }
+
+func (d *fakeDriver) AppliedMigrations(ctx context.Context, ex Executor) (map[int]bool, error) {
+	applied := map[int]bool{}
+	for v := range d.applied {
+		applied[v] = true
+	}
+	return applied, nil
+}
+
+func (d *fakeDriver) RecordMigration(ctx context.Context, ex Executor, version int) error {
+	d.applied[version] = true
+	return nil
+}
+
+// nonTransactionalFakeDriver is a fakeDriver that also implements
+// nonTransactionalDriver, used to exercise Migrate()'s no-shared-tx path
+// the same way DynamoSQLDriver uses it.
+type nonTransactionalFakeDriver struct {
+	fakeDriver
+}
+
+func (*nonTransactionalFakeDriver) nonTransactionalMigrations() {}
+
+var _ = Describe("func Migrate()", func() {
+	var (
+		db      *sql.DB
+		upCalls int
+		d       *fakeDriver
+	)
+
+	BeforeEach(func() {
+		var err error
+		db, err = sql.Open("sqlprojection-fake", "")
+		Expect(err).ShouldNot(HaveOccurred())
+
+		upCalls = 0
+		d = &fakeDriver{
+			migrations: []Migration{
+				recordingMigration{version: 1, upCalls: &upCalls},
+			},
+			applied: map[int]bool{},
+		}
+	})
+
+	AfterEach(func() {
+		db.Close()
+	})
+
+	It("applies a migration that has not yet been recorded", func() {
+		Expect(Migrate(context.Background(), db, d)).To(Succeed())
+		Expect(upCalls).To(Equal(1))
+		Expect(d.applied).To(HaveKey(1))
+	})
+
+	It("does not re-apply a migration that has already been recorded", func() {
+		Expect(Migrate(context.Background(), db, d)).To(Succeed())
+		Expect(Migrate(context.Background(), db, d)).To(Succeed())
+
+		Expect(upCalls).To(Equal(1))
+	})
+
+	It("applies newly added migrations without re-applying earlier ones", func() {
+		Expect(Migrate(context.Background(), db, d)).To(Succeed())
+
+		var upCalls2 int
+		d.migrations = append(d.migrations, recordingMigration{version: 2, upCalls: &upCalls2})
+
+		Expect(Migrate(context.Background(), db, d)).To(Succeed())
+		Expect(upCalls).To(Equal(1))
+		Expect(upCalls2).To(Equal(1))
+		Expect(d.applied).To(HaveKey(2))
+	})
+
+	It("does not open a transaction for a nonTransactionalDriver", func() {
+		noTxDB, err := sql.Open("sqlprojection-no-begin", "")
+		Expect(err).ShouldNot(HaveOccurred())
+		defer noTxDB.Close()
+
+		nd := &nonTransactionalFakeDriver{
+			fakeDriver: fakeDriver{
+				migrations: []Migration{
+					recordingMigration{version: 1, upCalls: &upCalls},
+				},
+				applied: map[int]bool{},
+			},
+		}
+
+		Expect(Migrate(context.Background(), noTxDB, nd)).To(Succeed())
+		Expect(upCalls).To(Equal(1))
+	})
+})
+
+var _ = Describe("built-in Driver.Migrations()", func() {
+	assertContiguous := func(d Driver) {
+		migrations := d.Migrations()
+		Expect(migrations).ToNot(BeEmpty())
+
+		for i, m := range migrations {
+			Expect(m.Version()).To(Equal(i + 1))
+		}
+	}
+
+	It("returns contiguous versions starting at 1 for PostgresDriver", func() {
+		assertContiguous(PostgresDriver)
+	})
+
+	It("returns contiguous versions starting at 1 for DynamoSQLDriver", func() {
+		assertContiguous(DynamoSQLDriver)
+	})
+})
+
+var _ = Describe("PostgresDriver.Migrations()", func() {
+	// These tests drive the real migration #1 DDL (not the fakeDriver used
+	// above) through a recording connection, so that a regression like
+	// non-idempotent DDL (which silently passed when only fakeDriver was
+	// exercised) is caught.
+	var db *sql.DB
+
+	BeforeEach(func() {
+		var err error
+		db, err = sql.Open("sqlprojection-recording", "postgres-migration-1")
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		db.Close()
+		delete(recordingExecs, "postgres-migration-1")
+	})
+
+	It("is safe to run against a fresh database and a legacy database alike", func() {
+		migration := PostgresDriver.Migrations()[0]
+
+		for i := 0; i < 2; i++ {
+			tx, err := db.BeginTx(context.Background(), nil)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			Expect(migration.Up(context.Background(), tx)).To(Succeed())
+			Expect(tx.Commit()).To(Succeed())
+		}
+
+		execs := *recordingExecs["postgres-migration-1"]
+		Expect(execs).To(HaveLen(2))
+
+		for _, stmt := range execs {
+			Expect(stmt).To(ContainSubstring("CREATE SCHEMA IF NOT EXISTS projection"))
+			Expect(stmt).To(ContainSubstring("CREATE TABLE IF NOT EXISTS projection.occ"))
+		}
+	})
+})
+
+var _ = Describe("DynamoSQLDriver.CreateSchema()", func() {
+	// godynamo maps a *sql.Tx onto DynamoDB's TransactWriteItems API, which
+	// rejects CREATE TABLE, so CreateSchema must issue migration #1's DDL
+	// directly against db rather than within a transaction.
+	It("does not open a transaction", func() {
+		db, err := sql.Open("sqlprojection-no-begin", "")
+		Expect(err).ShouldNot(HaveOccurred())
+		defer db.Close()
+
+		Expect(DynamoSQLDriver.CreateSchema(context.Background(), db)).To(Succeed())
+	})
+})
@@ -16,19 +16,18 @@ var PostgresDriver Driver = postgresDriver{}
 
 type postgresDriver struct{}
 
-func (postgresDriver) CreateSchema(ctx context.Context, db *sql.DB) error {
-	_, err := db.ExecContext(
-		ctx,
-		`CREATE SCHEMA projection;
-		CREATE TABLE projection.occ (
-			handler  BYTEA NOT NULL,
-			resource BYTEA NOT NULL,
-			version  BYTEA NOT NULL,
+func (d postgresDriver) CreateSchema(ctx context.Context, db *sql.DB) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-			PRIMARY KEY (handler, resource)
-		);`,
-	)
-	return err
+	if err := d.Migrations()[0].Up(ctx, tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (postgresDriver) DropSchema(ctx context.Context, db *sql.DB) error {
@@ -195,6 +194,122 @@ func (postgresDriver) DeleteResource(
 	return err
 }
 
+func (postgresDriver) QueryVersions(
+	ctx context.Context,
+	db *sql.DB,
+	h string,
+	resources [][]byte,
+) (map[string][]byte, error) {
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT
+			resource,
+			version
+		FROM projection.occ
+		WHERE handler = $1
+		AND resource = ANY($2)`,
+		h,
+		pq.Array(resources),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := map[string][]byte{}
+
+	for rows.Next() {
+		var r, v []byte
+		if err := rows.Scan(&r, &v); err != nil {
+			return nil, err
+		}
+		versions[string(r)] = v
+	}
+
+	return versions, rows.Err()
+}
+
+func (postgresDriver) DeleteResources(
+	ctx context.Context,
+	db *sql.DB,
+	h string,
+	resources [][]byte,
+) error {
+	_, err := db.ExecContext(
+		ctx,
+		`DELETE FROM projection.occ
+		WHERE handler = $1
+		AND resource = ANY($2)`,
+		h,
+		pq.Array(resources),
+	)
+
+	return err
+}
+
+// listResourcesPageSize is the number of rows fetched per round-trip by
+// ListResources.
+const listResourcesPageSize = 100
+
+func (postgresDriver) ListResources(
+	ctx context.Context,
+	db *sql.DB,
+	h string,
+	fn func(resource, version []byte) error,
+) error {
+	// Start with an empty key, which sorts below every non-empty resource
+	// key, so the first page begins at the start of the handler's
+	// resources.
+	after := []byte{}
+
+	for {
+		rows, err := db.QueryContext(
+			ctx,
+			`SELECT
+				resource,
+				version
+			FROM projection.occ
+			WHERE handler = $1
+			AND resource > $2
+			ORDER BY resource
+			LIMIT $3`,
+			h,
+			after,
+			listResourcesPageSize,
+		)
+		if err != nil {
+			return err
+		}
+
+		var n int
+		for rows.Next() {
+			var r, v []byte
+			if err := rows.Scan(&r, &v); err != nil {
+				rows.Close()
+				return err
+			}
+
+			if err := fn(r, v); err != nil {
+				rows.Close()
+				return err
+			}
+
+			after = r
+			n++
+		}
+
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}
+
+		if n < listResourcesPageSize {
+			return nil
+		}
+	}
+}
+
 func (postgresDriver) isDup(err error) bool {
 	{
 		var e *pq.Error
@@ -211,4 +326,95 @@ func (postgresDriver) isDup(err error) bool {
 	}
 
 	return false
-}
\ No newline at end of file
+}
+
+// postgresMigrationLockKey is an arbitrary, fixed key used with
+// pg_advisory_xact_lock to serialize migrations of the projection schema
+// across concurrent processes.
+const postgresMigrationLockKey = 0x70726f6a // "proj"
+
+func (postgresDriver) lockMigrations(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, postgresMigrationLockKey)
+	return err
+}
+
+// Migrations returns the built-in migrations that create and evolve the
+// schema used by PostgresDriver. Migration #1 creates the same schema as
+// the original, non-migration-aware CreateSchema().
+func (postgresDriver) Migrations() []Migration {
+	return []Migration{
+		migration{
+			version: 1,
+			up: func(ctx context.Context, ex Executor) error {
+				_, err := ex.ExecContext(
+					ctx,
+					`CREATE SCHEMA IF NOT EXISTS projection;
+					CREATE TABLE IF NOT EXISTS projection.occ (
+						handler  BYTEA NOT NULL,
+						resource BYTEA NOT NULL,
+						version  BYTEA NOT NULL,
+
+						PRIMARY KEY (handler, resource)
+					);`,
+				)
+				return err
+			},
+			down: func(ctx context.Context, ex Executor) error {
+				_, err := ex.ExecContext(ctx, `DROP SCHEMA IF EXISTS projection CASCADE`)
+				return err
+			},
+		},
+	}
+}
+
+// postgresDriverName is the value recorded in the driver column of the
+// schema_migrations table for migrations applied by PostgresDriver.
+const postgresDriverName = "postgres"
+
+func (postgresDriver) CreateMigrationsTable(ctx context.Context, ex Executor) error {
+	_, err := ex.ExecContext(
+		ctx,
+		`CREATE SCHEMA IF NOT EXISTS projection;
+		CREATE TABLE IF NOT EXISTS projection.schema_migrations (
+			driver  TEXT NOT NULL,
+			version INT NOT NULL,
+
+			PRIMARY KEY (driver, version)
+		);`,
+	)
+	return err
+}
+
+func (postgresDriver) AppliedMigrations(ctx context.Context, ex Executor) (map[int]bool, error) {
+	rows, err := ex.QueryContext(
+		ctx,
+		`SELECT version FROM projection.schema_migrations WHERE driver = $1`,
+		postgresDriverName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]bool{}
+
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		applied[v] = true
+	}
+
+	return applied, rows.Err()
+}
+
+func (postgresDriver) RecordMigration(ctx context.Context, ex Executor, version int) error {
+	_, err := ex.ExecContext(
+		ctx,
+		`INSERT INTO projection.schema_migrations (driver, version) VALUES ($1, $2)`,
+		postgresDriverName,
+		version,
+	)
+	return err
+}
@@ -0,0 +1,73 @@
+package sqlprojection
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/dezlitz/projectionkit/internal/identity"
+	"github.com/dogmatiq/dogma"
+)
+
+// ResourceRepository provides access to the OCC resource versions tracked
+// on behalf of a specific handler, independently of event handling. It is
+// intended for use by administration tooling that needs to inspect or
+// repair a projection's resource versions, or build status dashboards
+// cheaply.
+type ResourceRepository struct {
+	db     *sql.DB
+	driver Driver
+	key    string
+}
+
+// NewResourceRepository returns a ResourceRepository that manipulates the
+// resource versions tracked on behalf of h, within db, using d.
+func NewResourceRepository(db *sql.DB, d Driver, h dogma.ProjectionMessageHandler) *ResourceRepository {
+	return &ResourceRepository{
+		db:     db,
+		driver: d,
+		key:    identity.Key(h),
+	}
+}
+
+// ResourceVersion returns the current version of the resource r.
+func (rr *ResourceRepository) ResourceVersion(ctx context.Context, r []byte) ([]byte, error) {
+	return rr.driver.QueryVersion(ctx, rr.db, rr.key, r)
+}
+
+// StoreResourceVersion unconditionally sets the version of the resource r
+// to v, bypassing the OCC check performed by HandleEvent.
+func (rr *ResourceRepository) StoreResourceVersion(ctx context.Context, r, v []byte) error {
+	return rr.driver.StoreVersion(ctx, rr.db, rr.key, r, v)
+}
+
+// DeleteResource removes all information about the resource r.
+func (rr *ResourceRepository) DeleteResource(ctx context.Context, r []byte) error {
+	return rr.driver.DeleteResource(ctx, rr.db, rr.key, r)
+}
+
+// QueryVersions returns the current versions of the given resources, keyed
+// by resource. Resources with no recorded version are omitted from the
+// result.
+func (rr *ResourceRepository) QueryVersions(ctx context.Context, resources [][]byte) (map[string][]byte, error) {
+	return rr.driver.QueryVersions(ctx, rr.db, rr.key, resources)
+}
+
+// DeleteResources removes all information about the given resources.
+func (rr *ResourceRepository) DeleteResources(ctx context.Context, resources [][]byte) error {
+	return rr.driver.DeleteResources(ctx, rr.db, rr.key, resources)
+}
+
+// ListResources calls fn once for each resource tracked on behalf of the
+// repository's handler, passing the resource and its current version,
+// streaming results using keyset pagination.
+//
+// Iteration stops, and ListResources returns fn's error, as soon as fn
+// returns a non-nil error.
+func (rr *ResourceRepository) ListResources(ctx context.Context, fn func(resource, version []byte) error) error {
+	return rr.driver.ListResources(ctx, rr.db, rr.key, fn)
+}
+
+// Close closes the repository. It does not close the underlying *sql.DB.
+func (rr *ResourceRepository) Close() error {
+	return nil
+}
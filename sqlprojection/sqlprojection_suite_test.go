@@ -0,0 +1,13 @@
+package sqlprojection_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestSQLProjection(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "sqlprojection Suite")
+}